@@ -0,0 +1,62 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlsmith
+
+import "github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+
+// makeJoinExpr picks a table to join against left and, if one is found,
+// the ON condition to join it on. When avoidCartesianProducts is set, it
+// prefers a table connected to left by a foreign key and joins on the FK
+// column pairs, returning a nil condition only when no related table
+// exists; otherwise it falls back to picking an arbitrary table with no
+// join condition, the prior behavior.
+func (s *Smither) makeJoinExpr(left *tableRef) (*tableRef, tree.Expr, bool) {
+	if s.avoidCartesianProducts {
+		if right, fk, ok := s.getRelatedTable(left); ok {
+			return right, makeFKJoinCond(left, right, fk), true
+		}
+	}
+	right, ok := s.getRandTable()
+	if !ok {
+		return nil, nil, false
+	}
+	return right, nil, true
+}
+
+// makeFKJoinCond builds the equi-join condition `left.c1 = right.c1 AND
+// ...` for the column pairs described by fk, where fk.ReferencingColumns
+// belong to left and fk.ReferencedColumns belong to right.
+func makeFKJoinCond(left, right *tableRef, fk fkRef) tree.Expr {
+	var cond tree.Expr
+	for i := range fk.ReferencingColumns {
+		eq := &tree.ComparisonExpr{
+			Operator: tree.EQ,
+			Left: &tree.ColumnItem{
+				TableName:  left.TableName.ToUnresolvedObjectName(),
+				ColumnName: fk.ReferencingColumns[i],
+			},
+			Right: &tree.ColumnItem{
+				TableName:  right.TableName.ToUnresolvedObjectName(),
+				ColumnName: fk.ReferencedColumns[i],
+			},
+		}
+		if cond == nil {
+			cond = eq
+		} else {
+			cond = &tree.AndExpr{Left: cond, Right: eq}
+		}
+	}
+	return cond
+}
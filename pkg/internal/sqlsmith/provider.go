@@ -0,0 +1,219 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlsmith
+
+import (
+	gosql "database/sql"
+	"math/rand"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+)
+
+// SchemaProvider supplies the tables and indexes a Smither generates
+// statements against. dbSchemaProvider reads this from a live cluster;
+// sqlProvider parses it out of a block of DDL instead, so Smither can
+// generate statements without a cockroach process to talk to.
+type SchemaProvider interface {
+	GetSchema() ([]*tableRef, map[tree.TableName]map[tree.Name]*tree.CreateIndex, error)
+}
+
+// enumSchemaProvider is implemented by SchemaProviders that resolve an enum
+// registry while loading tables and can hand it back, so ReloadSchemas can
+// reuse it instead of re-querying for enums it already has.
+type enumSchemaProvider interface {
+	Enums() map[tree.Name]*types.T
+}
+
+// dbSchemaProvider is the default SchemaProvider, backed by a live database
+// connection.
+type dbSchemaProvider struct {
+	db *gosql.DB
+
+	// enums caches the registry resolved by the most recent GetSchema call,
+	// so Enums() doesn't need to re-query pg_type.
+	enums map[tree.Name]*types.T
+}
+
+// GetSchema implements the SchemaProvider interface.
+func (p *dbSchemaProvider) GetSchema() (
+	[]*tableRef, map[tree.TableName]map[tree.Name]*tree.CreateIndex, error,
+) {
+	enums, err := extractEnums(p.db)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.enums = enums
+	tables, err := extractTables(p.db, enums)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexes, err := extractIndexes(p.db, tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	fks, err := extractForeignKeys(p.db, tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range tables {
+		t.ForeignKeys = fks[*t.TableName]
+	}
+	return tables, indexes, nil
+}
+
+// Enums implements enumSchemaProvider.
+func (p *dbSchemaProvider) Enums() map[tree.Name]*types.T {
+	return p.enums
+}
+
+// sqlProvider implements SchemaProvider by parsing CREATE TABLE and CREATE
+// INDEX statements out of a string of DDL, rather than querying a live
+// cluster. This lets Smither generate statements for a schema that isn't
+// loaded anywhere, e.g. to reproduce a customer's schema from a dump, or
+// to generate queries in CI without spinning up cockroach.
+type sqlProvider struct {
+	ddl string
+}
+
+// GetSchema implements the SchemaProvider interface.
+func (p *sqlProvider) GetSchema() (
+	[]*tableRef, map[tree.TableName]map[tree.Name]*tree.CreateIndex, error,
+) {
+	stmts, err := parser.Parse(p.ddl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Foreign keys can omit the referenced column list, in which case it
+	// defaults to the referenced table's primary key -- which may be
+	// declared later in the DDL than the table doing the referencing. So
+	// columns and primary keys are collected in a first pass, and foreign
+	// keys, which may need another table's primary key to resolve, in a
+	// second.
+	var tables []*tableRef
+	pks := map[tree.TableName][]tree.Name{}
+	indexes := map[tree.TableName]map[tree.Name]*tree.CreateIndex{}
+
+	for _, stmt := range stmts {
+		switch n := stmt.AST.(type) {
+		case *tree.CreateTable:
+			t := &tableRef{TableName: &n.Table}
+			for _, def := range n.Defs {
+				switch d := def.(type) {
+				case *tree.ColumnTableDef:
+					col := *d
+					t.Columns = append(t.Columns, &col)
+					if d.PrimaryKey.IsPrimaryKey {
+						pks[n.Table] = []tree.Name{d.Name}
+					}
+				case *tree.UniqueConstraintTableDef:
+					if d.PrimaryKey {
+						cols := make([]tree.Name, len(d.Columns))
+						for i, c := range d.Columns {
+							cols[i] = c.Column
+						}
+						pks[n.Table] = cols
+					}
+				}
+			}
+			tables = append(tables, t)
+		case *tree.CreateIndex:
+			if indexes[n.Table] == nil {
+				indexes[n.Table] = map[tree.Name]*tree.CreateIndex{}
+			}
+			idx := *n
+			indexes[n.Table][n.Name] = &idx
+		}
+	}
+
+	for _, stmt := range stmts {
+		n, ok := stmt.AST.(*tree.CreateTable)
+		if !ok {
+			continue
+		}
+		t := tableByName(tables, n.Table)
+		for _, def := range n.Defs {
+			switch d := def.(type) {
+			case *tree.ColumnTableDef:
+				if d.References.Table == nil {
+					continue
+				}
+				refCols := []tree.Name{d.References.Col}
+				if d.References.Col == "" {
+					refCols = pks[*d.References.Table]
+				}
+				if len(refCols) != 1 {
+					// Can't resolve the referenced table's primary key, or it
+					// isn't a single column; skip rather than guess.
+					continue
+				}
+				t.ForeignKeys = append(t.ForeignKeys, fkRef{
+					ReferencedTable:    d.References.Table,
+					ReferencingColumns: []tree.Name{d.Name},
+					ReferencedColumns:  refCols,
+				})
+			case *tree.ForeignKeyConstraintTableDef:
+				toCols := d.ToCols
+				if len(toCols) == 0 {
+					toCols = pks[d.Table]
+				}
+				if len(toCols) != len(d.FromCols) {
+					// Can't resolve the referenced table's primary key, or its
+					// column count doesn't match; skip rather than guess.
+					continue
+				}
+				t.ForeignKeys = append(t.ForeignKeys, fkRef{
+					ReferencedTable:    &d.Table,
+					ReferencingColumns: append([]tree.Name(nil), d.FromCols...),
+					ReferencedColumns:  append([]tree.Name(nil), toCols...),
+				})
+			}
+		}
+	}
+
+	return tables, indexes, nil
+}
+
+// tableByName returns the tableRef for name out of tables, which must
+// contain it.
+func tableByName(tables []*tableRef, name tree.TableName) *tableRef {
+	for _, t := range tables {
+		if *t.TableName == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// NewFromDDL creates a Smither whose schema is parsed from ddl, a string of
+// CREATE TABLE and CREATE INDEX statements, rather than read from a live
+// database connection. Foreign keys, computed columns, and nullability
+// declared in ddl populate the same tableRef fields the database-backed
+// loader populates.
+func NewFromDDL(rnd *rand.Rand, ddl string, opts ...SmitherOption) (*Smither, error) {
+	s := &Smither{
+		rnd:      rnd,
+		provider: &sqlProvider{ddl: ddl},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.ReloadSchemas(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
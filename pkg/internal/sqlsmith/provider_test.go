@@ -0,0 +1,119 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlsmith
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlProviderGetSchema(t *testing.T) {
+	const ddl = `
+CREATE TABLE customers (id INT PRIMARY KEY, region_id INT, code INT);
+CREATE TABLE orders (
+	id INT PRIMARY KEY,
+	customer_id INT REFERENCES customers(id),
+	region_id INT,
+	code INT,
+	FOREIGN KEY (region_id, code) REFERENCES customers (region_id, code)
+);
+CREATE INDEX orders_customer_idx ON orders (customer_id);
+`
+	p := &sqlProvider{ddl: ddl}
+	tables, indexes, err := p.GetSchema()
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+
+	var orders *tableRef
+	for _, tbl := range tables {
+		if tbl.TableName.Table() == "orders" {
+			orders = tbl
+		}
+	}
+	require.NotNil(t, orders)
+	require.Len(t, orders.ForeignKeys, 2)
+
+	var inline, composite *fkRef
+	for i := range orders.ForeignKeys {
+		fk := &orders.ForeignKeys[i]
+		if len(fk.ReferencingColumns) == 1 {
+			inline = fk
+		} else {
+			composite = fk
+		}
+	}
+
+	require.NotNil(t, inline, "inline REFERENCES column constraint should produce a foreign key")
+	require.Equal(t, []tree.Name{"customer_id"}, inline.ReferencingColumns)
+	require.Equal(t, []tree.Name{"id"}, inline.ReferencedColumns)
+
+	require.NotNil(t, composite, "table-level FOREIGN KEY constraint should produce a foreign key")
+	require.Equal(t, []tree.Name{"region_id", "code"}, composite.ReferencingColumns)
+	require.Equal(t, []tree.Name{"region_id", "code"}, composite.ReferencedColumns)
+
+	idxs := indexes[*orders.TableName]
+	require.Contains(t, idxs, tree.Name("orders_customer_idx"))
+}
+
+func TestSqlProviderGetSchemaOmittedFKColumns(t *testing.T) {
+	const ddl = `
+CREATE TABLE employees (id INT PRIMARY KEY, name STRING);
+CREATE TABLE offices (region_id INT, code INT, PRIMARY KEY (region_id, code));
+CREATE TABLE no_pk (val INT);
+CREATE TABLE assignments (
+	manager_id INT REFERENCES employees,
+	region_id INT,
+	code INT,
+	bogus_id INT REFERENCES no_pk,
+	FOREIGN KEY (region_id, code) REFERENCES offices
+);
+`
+	p := &sqlProvider{ddl: ddl}
+	tables, _, err := p.GetSchema()
+	require.NoError(t, err)
+
+	var assignments *tableRef
+	for _, tbl := range tables {
+		if tbl.TableName.Table() == "assignments" {
+			assignments = tbl
+		}
+	}
+	require.NotNil(t, assignments)
+
+	var toEmployees, toOffices *fkRef
+	for i := range assignments.ForeignKeys {
+		fk := &assignments.ForeignKeys[i]
+		switch fk.ReferencedTable.Table() {
+		case "employees":
+			toEmployees = fk
+		case "offices":
+			toOffices = fk
+		case "no_pk":
+			t.Fatalf("FK to a table without a resolvable primary key should have been skipped, got %+v", fk)
+		}
+	}
+
+	require.NotNil(t, toEmployees, "single-column REFERENCES with no column should resolve to the referenced table's PK")
+	require.Equal(t, []tree.Name{"manager_id"}, toEmployees.ReferencingColumns)
+	require.Equal(t, []tree.Name{"id"}, toEmployees.ReferencedColumns)
+
+	require.NotNil(t, toOffices, "table-level FOREIGN KEY with no column list should resolve to the referenced table's composite PK")
+	require.Equal(t, []tree.Name{"region_id", "code"}, toOffices.ReferencingColumns)
+	require.Equal(t, []tree.Name{"region_id", "code"}, toOffices.ReferencedColumns)
+
+	require.Len(t, assignments.ForeignKeys, 2, "FK to a table with no primary key should be skipped rather than guessed")
+}
@@ -28,8 +28,9 @@ import (
 
 // tableRef represents a table and its columns.
 type tableRef struct {
-	TableName *tree.TableName
-	Columns   []*tree.ColumnTableDef
+	TableName   *tree.TableName
+	Columns     []*tree.ColumnTableDef
+	ForeignKeys []fkRef
 }
 
 type tableRefs []*tableRef
@@ -38,29 +39,118 @@ func (t tableRefs) Pop() (*tableRef, tableRefs) {
 	return t[0], t[1:]
 }
 
-// ReloadSchemas loads tables from the database.
+// fkRef describes a foreign key relationship: ReferencingColumns of the
+// table that owns the fkRef reference ReferencedColumns of ReferencedTable,
+// in positional order.
+type fkRef struct {
+	ReferencedTable    *tree.TableName
+	ReferencingColumns []tree.Name
+	ReferencedColumns  []tree.Name
+}
+
+// ReloadSchemas loads tables and indexes from s.provider.
 func (s *Smither) ReloadSchemas() error {
-	if s.db == nil {
+	if s.provider == nil {
 		return nil
 	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
+
 	var err error
-	s.tables, err = extractTables(s.db)
+	s.tables, s.indexes, err = s.provider.GetSchema()
 	if err != nil {
 		return err
 	}
-	s.indexes, err = extractIndexes(s.db, s.tables)
-	return err
+
+	// Views, materialized views, and enums are only available when backed by
+	// a live database; sqlProvider doesn't support them.
+	if s.db == nil {
+		return nil
+	}
+	if ep, ok := s.provider.(enumSchemaProvider); ok {
+		// s.provider already resolved an enum registry while loading tables;
+		// reuse it instead of re-querying pg_type for the same
+		// thing, which would otherwise be a second DB round trip that isn't
+		// even guaranteed to see the same snapshot.
+		s.enums = ep.Enums()
+	} else {
+		s.enums, err = extractEnums(s.db)
+		if err != nil {
+			return err
+		}
+	}
+	if s.includeViews {
+		if s.views, err = extractViews(s.db, s.enums); err != nil {
+			return err
+		}
+	}
+	if s.includeMaterializedViews {
+		if s.matviews, err = extractMatviews(s.db, s.enums); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Smither) getRandTable() (*tableRef, bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	if len(s.tables) == 0 {
+	candidates := make(tableRefs, 0, len(s.tables)+len(s.views)+len(s.matviews))
+	candidates = append(candidates, s.tables...)
+	if s.includeViews {
+		candidates = append(candidates, s.views...)
+	}
+	if s.includeMaterializedViews {
+		candidates = append(candidates, s.matviews...)
+	}
+	if len(candidates) == 0 {
 		return nil, false
 	}
-	return s.tables[s.rnd.Intn(len(s.tables))], true
+	return candidates[s.rnd.Intn(len(candidates))], true
+}
+
+// getRelatedTable returns a table connected to t by a foreign key, in
+// either direction, along with the fkRef describing which columns the join
+// should be made on. It's used to bias join generation towards foreign key
+// edges -- see AvoidCartesianProducts -- instead of joining two arbitrary
+// tables together. Self-referencing FKs are skipped, since makeFKJoinCond
+// has no way to alias the same table for both sides of the join.
+func (s *Smither) getRelatedTable(t *tableRef) (*tableRef, fkRef, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var candidates []fkRef
+	for _, fk := range t.ForeignKeys {
+		if *fk.ReferencedTable == *t.TableName {
+			continue
+		}
+		candidates = append(candidates, fk)
+	}
+	for _, other := range s.tables {
+		if *other.TableName == *t.TableName {
+			continue
+		}
+		for _, fk := range other.ForeignKeys {
+			if *fk.ReferencedTable != *t.TableName {
+				continue
+			}
+			candidates = append(candidates, fkRef{
+				ReferencedTable:    other.TableName,
+				ReferencingColumns: fk.ReferencedColumns,
+				ReferencedColumns:  fk.ReferencingColumns,
+			})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fkRef{}, false
+	}
+	fk := candidates[s.rnd.Intn(len(candidates))]
+	for _, other := range s.tables {
+		if *other.TableName == *fk.ReferencedTable {
+			return other, fk, true
+		}
+	}
+	return nil, fkRef{}, false
 }
 
 func (s *Smither) getIndexes(table tree.TableName) map[tree.Name]*tree.CreateIndex {
@@ -95,8 +185,41 @@ func (s *Smither) getRandIndex() (*tree.TableIndexName, *tree.CreateIndex, bool)
 	return s.getRandTableIndex(*tableRef.TableName)
 }
 
-func extractTables(db *gosql.DB) ([]*tableRef, error) {
-	rows, err := db.Query(`
+// extractTables loads the plain tables (excluding views and materialized
+// views) visible to db.
+func extractTables(db *gosql.DB, enums map[tree.Name]*types.T) ([]*tableRef, error) {
+	return extractColumnTableRefs(db, enums, `
+	table_name NOT IN (SELECT table_name FROM information_schema.views WHERE table_schema = 'public')
+	AND table_name NOT IN (SELECT matviewname FROM pg_matviews WHERE schemaname = 'public')
+`)
+}
+
+// extractViews loads views visible to db, using the same tableRef shape as
+// extractTables so the rest of Smither can treat them uniformly.
+func extractViews(db *gosql.DB, enums map[tree.Name]*types.T) ([]*tableRef, error) {
+	return extractColumnTableRefs(db, enums, `
+	table_name IN (SELECT table_name FROM information_schema.views WHERE table_schema = 'public')
+`)
+}
+
+// extractMatviews loads materialized views visible to db, using the same
+// tableRef shape as extractTables so the rest of Smither can treat them
+// uniformly.
+func extractMatviews(db *gosql.DB, enums map[tree.Name]*types.T) ([]*tableRef, error) {
+	return extractColumnTableRefs(db, enums, `
+	table_name IN (SELECT matviewname FROM pg_matviews WHERE schemaname = 'public')
+`)
+}
+
+// extractColumnTableRefs loads tableRefs from information_schema.columns,
+// further restricted by extraFilter, which is ANDed onto the usual
+// table_schema = 'public' predicate. extractTables, extractViews, and
+// extractMatviews all share this since the only difference between them is
+// which relations to include.
+func extractColumnTableRefs(
+	db *gosql.DB, enums map[tree.Name]*types.T, extraFilter string,
+) ([]*tableRef, error) {
+	rows, err := db.Query(fmt.Sprintf(`
 SELECT
 	table_catalog,
 	table_schema,
@@ -110,9 +233,10 @@ FROM
 	information_schema.columns
 WHERE
 	table_schema = 'public'
+	AND %s
 ORDER BY
 	table_catalog, table_schema, table_name
-	`)
+	`, extraFilter))
 	// TODO(justin): have a flag that includes system tables?
 	if err != nil {
 		return nil, err
@@ -159,7 +283,7 @@ ORDER BY
 			currentCols = nil
 		}
 
-		coltyp := typeFromName(typ)
+		coltyp := typeFromName(typ, enums)
 		column := tree.ColumnTableDef{
 			Name: col,
 			Type: coltyp,
@@ -181,6 +305,59 @@ ORDER BY
 	return tables, rows.Err()
 }
 
+// typeFromName resolves a crdb_sql_type string, as reported by
+// information_schema.columns, to a types.T. Names that match a loaded
+// user-defined ENUM type are resolved via enums instead of the built-in
+// type name table.
+func typeFromName(typ string, enums map[tree.Name]*types.T) *types.T {
+	if t, ok := enums[tree.Name(typ)]; ok {
+		return t
+	}
+	for _, t := range types.AnyNonArray {
+		if strings.EqualFold(t.String(), typ) {
+			return t
+		}
+	}
+	return types.Unknown
+}
+
+// extractEnums loads the user-defined ENUM types visible to db, keyed by
+// type name, from pg_type (rows with typtype 'e' in the public namespace);
+// pg_enum is not read. sqlsmith doesn't model individual enum
+// labels; it treats enum values like strings for the purposes of
+// comparisons and casts, which is enough to exercise enum arithmetic in
+// operators and functions.
+func extractEnums(db *gosql.DB) (map[tree.Name]*types.T, error) {
+	rows, err := db.Query(`
+SELECT
+	t.typname,
+	t.oid
+FROM
+	pg_type AS t
+	JOIN pg_namespace AS n ON n.oid = t.typnamespace
+WHERE
+	t.typtype = 'e' AND n.nspname = 'public'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enums := map[tree.Name]*types.T{}
+	for rows.Next() {
+		var name tree.Name
+		var enumOid oid.Oid
+		if err := rows.Scan(&name, &enumOid); err != nil {
+			return nil, err
+		}
+		enumTyp := *types.String
+		enumTyp.InternalType.Oid = enumOid
+		enumTyp.InternalType.Name = string(name)
+		enums[name] = &enumTyp
+	}
+	return enums, rows.Err()
+}
+
 func extractIndexes(
 	db *gosql.DB, tables tableRefs,
 ) (map[tree.TableName]map[tree.Name]*tree.CreateIndex, error) {
@@ -228,6 +405,97 @@ func extractIndexes(
 	return ret, nil
 }
 
+// extractForeignKeys loads the foreign key relationships for tables, keyed
+// by the referencing table's name. It joins referential_constraints to
+// key_column_usage twice, once for the referencing side and once for the
+// referenced side, to recover the column pairs in ordinal order.
+func extractForeignKeys(db *gosql.DB, tables tableRefs) (map[tree.TableName][]fkRef, error) {
+	byName := make(map[tree.TableName]*tableRef, len(tables))
+	for _, t := range tables {
+		byName[*t.TableName] = t
+	}
+
+	rows, err := db.Query(`
+SELECT
+	kcu.table_catalog,
+	kcu.table_schema,
+	kcu.table_name,
+	kcu.column_name,
+	ccu.table_catalog,
+	ccu.table_schema,
+	ccu.table_name,
+	ccu.column_name,
+	rc.constraint_name
+FROM
+	information_schema.referential_constraints AS rc
+	JOIN information_schema.key_column_usage AS kcu ON
+			kcu.constraint_catalog = rc.constraint_catalog
+			AND kcu.constraint_schema = rc.constraint_schema
+			AND kcu.constraint_name = rc.constraint_name
+	JOIN information_schema.key_column_usage AS ccu ON
+			ccu.constraint_catalog = rc.unique_constraint_catalog
+			AND ccu.constraint_schema = rc.unique_constraint_schema
+			AND ccu.constraint_name = rc.unique_constraint_name
+			AND ccu.ordinal_position = kcu.ordinal_position
+ORDER BY
+	kcu.table_catalog, kcu.table_schema, kcu.table_name, rc.constraint_name, kcu.ordinal_position
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct {
+		table      tree.TableName
+		constraint tree.Name
+	}
+	var order []key
+	byKey := map[key]*fkRef{}
+
+	for rows.Next() {
+		var catalog, schema, name, col tree.Name
+		var refCatalog, refSchema, refName, refCol tree.Name
+		var constraint tree.Name
+		if err := rows.Scan(
+			&catalog, &schema, &name, &col,
+			&refCatalog, &refSchema, &refName, &refCol,
+			&constraint,
+		); err != nil {
+			return nil, err
+		}
+		if schema != "public" || refSchema != "public" {
+			continue
+		}
+		tn := *tree.NewTableName(catalog, name)
+		if _, ok := byName[tn]; !ok {
+			continue
+		}
+		refTN := tree.NewTableName(refCatalog, refName)
+		if _, ok := byName[*refTN]; !ok {
+			continue
+		}
+
+		k := key{table: tn, constraint: constraint}
+		fk, ok := byKey[k]
+		if !ok {
+			fk = &fkRef{ReferencedTable: refTN}
+			byKey[k] = fk
+			order = append(order, k)
+		}
+		fk.ReferencingColumns = append(fk.ReferencingColumns, col)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, refCol)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[tree.TableName][]fkRef, len(tables))
+	for _, k := range order {
+		ret[k.table] = append(ret[k.table], *byKey[k])
+	}
+	return ret, nil
+}
+
 type operator struct {
 	*tree.BinOp
 	Operator tree.BinaryOperator
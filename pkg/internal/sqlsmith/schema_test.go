@@ -0,0 +1,105 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlsmith
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testTableName(name string) *tree.TableName {
+	return tree.NewTableName("db", tree.Name(name))
+}
+
+func TestTypeFromName(t *testing.T) {
+	enumTyp := *types.String
+	enumTyp.InternalType.Name = "my_enum"
+	enums := map[tree.Name]*types.T{"my_enum": &enumTyp}
+
+	testCases := []struct {
+		name string
+		typ  string
+		want *types.T
+	}{
+		{name: "builtin", typ: types.Int.String(), want: types.Int},
+		{name: "enum", typ: "my_enum", want: &enumTyp},
+		{name: "unknown", typ: "not_a_real_type", want: types.Unknown},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Same(t, tc.want, typeFromName(tc.typ, enums))
+		})
+	}
+}
+
+func TestGetRelatedTable(t *testing.T) {
+	parent := &tableRef{TableName: testTableName("parent")}
+	child := &tableRef{
+		TableName: testTableName("child"),
+		ForeignKeys: []fkRef{{
+			ReferencedTable:    parent.TableName,
+			ReferencingColumns: []tree.Name{"parent_id"},
+			ReferencedColumns:  []tree.Name{"id"},
+		}},
+	}
+	lonely := &tableRef{TableName: testTableName("lonely")}
+
+	s := &Smither{
+		rnd:    rand.New(rand.NewSource(1)),
+		tables: tableRefs{parent, child, lonely},
+	}
+
+	t.Run("referencing side", func(t *testing.T) {
+		related, fk, ok := s.getRelatedTable(child)
+		require.True(t, ok)
+		require.Equal(t, parent, related)
+		require.Equal(t, []tree.Name{"parent_id"}, fk.ReferencingColumns)
+		require.Equal(t, []tree.Name{"id"}, fk.ReferencedColumns)
+	})
+
+	t.Run("referenced side", func(t *testing.T) {
+		related, fk, ok := s.getRelatedTable(parent)
+		require.True(t, ok)
+		require.Equal(t, child, related)
+		require.Equal(t, []tree.Name{"id"}, fk.ReferencingColumns)
+		require.Equal(t, []tree.Name{"parent_id"}, fk.ReferencedColumns)
+	})
+
+	t.Run("no relation", func(t *testing.T) {
+		_, _, ok := s.getRelatedTable(lonely)
+		require.False(t, ok)
+	})
+}
+
+func TestGetRelatedTableSelfReference(t *testing.T) {
+	employees := &tableRef{TableName: testTableName("employees")}
+	employees.ForeignKeys = []fkRef{{
+		ReferencedTable:    employees.TableName,
+		ReferencingColumns: []tree.Name{"manager_id"},
+		ReferencedColumns:  []tree.Name{"id"},
+	}}
+
+	s := &Smither{
+		rnd:    rand.New(rand.NewSource(1)),
+		tables: tableRefs{employees},
+	}
+
+	_, _, ok := s.getRelatedTable(employees)
+	require.False(t, ok, "a self-referencing FK should not be offered as a join partner")
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlsmith
+
+import (
+	gosql "database/sql"
+	"math/rand"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// Smither generates random SQL statements against a loaded schema.
+type Smither struct {
+	rnd      *rand.Rand
+	db       *gosql.DB
+	provider SchemaProvider
+	lock     syncutil.RWMutex
+
+	tables   tableRefs
+	views    tableRefs
+	matviews tableRefs
+	indexes  map[tree.TableName]map[tree.Name]*tree.CreateIndex
+	enums    map[tree.Name]*types.T
+
+	avoidCartesianProducts   bool
+	includeViews             bool
+	includeMaterializedViews bool
+}
+
+// NewSmither creates a new Smither that generates statements using the
+// schema loaded from db.
+func NewSmither(db *gosql.DB, rnd *rand.Rand, opts ...SmitherOption) (*Smither, error) {
+	s := &Smither{
+		rnd:      rnd,
+		db:       db,
+		provider: &dbSchemaProvider{db: db},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.ReloadSchemas(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SmitherOption configures a Smither during construction.
+type SmitherOption func(*Smither)
+
+// AvoidCartesianProducts causes the Smither to prefer joining a table to
+// one it shares a foreign key with, rather than picking two unrelated
+// tables and relying on WHERE clauses to narrow the result down after the
+// fact. This produces more realistic query shapes and exercises the join
+// planner and lookup joins far more often than unconstrained cross joins
+// do.
+func AvoidCartesianProducts() SmitherOption {
+	return func(s *Smither) {
+		s.avoidCartesianProducts = true
+	}
+}
+
+// IncludeViews causes the Smither to include views as candidate tables
+// when building statements, in addition to ordinary tables.
+func IncludeViews() SmitherOption {
+	return func(s *Smither) {
+		s.includeViews = true
+	}
+}
+
+// IncludeMaterializedViews causes the Smither to include materialized
+// views as candidate tables when building statements, in addition to
+// ordinary tables.
+func IncludeMaterializedViews() SmitherOption {
+	return func(s *Smither) {
+		s.includeMaterializedViews = true
+	}
+}